@@ -0,0 +1,139 @@
+// Package i18n holds every bot-facing string in Russian and English so
+// replies can be rendered in the chat's configured language.
+package i18n
+
+// DefaultLang is used whenever a chat hasn't chosen a language yet, and
+// as the fallback when a key is missing for the chosen one.
+const DefaultLang = "ru"
+
+var messages = map[string]map[string]string{
+	"ru": {
+		"main_menu":                 "Выберите действие:",
+		"btn_start_timer":           "Старт таймера",
+		"btn_list_timers":           "Мои таймеры",
+		"btn_show_logs":             "Показать логи",
+		"btn_settings":              "Настройки",
+		"btn_presets":               "Пресеты",
+		"use_buttons":               "Используй кнопки для работы с таймерами.",
+		"presets_empty":             "У вас нет пресетов. Создайте: /preset <имя> <минуты>",
+		"presets_header":            "Ваши пресеты:",
+		"preset_usage":              "Использование: /preset <имя> <минуты> | /preset list | /preset del <имя>",
+		"preset_saved":              "Пресет \"%s\" сохранён (%d мин).",
+		"preset_deleted":            "Пресет \"%s\" удалён.",
+		"preset_not_found":          "Пресет не найден.",
+		"preset_list_entry":         "\n%s — %d мин",
+		"preset_button_label":       "%s (%d мин)",
+		"enter_timer_name":          "Введите название таймера:",
+		"enter_minutes_or_schedule": "Введите время в минутах, либо выберите повторяющееся расписание:",
+		"btn_daily":                 "Ежедневно",
+		"btn_cron":                  "Cron",
+		"enter_daily_time":          "Введите время ежедневного запуска в формате ЧЧ:ММ:",
+		"enter_cron_expr":           "Введите cron-выражение (минута час день месяц день_недели):",
+		"invalid_daily_time":        "Введите корректное время в формате ЧЧ:ММ.",
+		"invalid_cron":              "Введите корректное cron-выражение.",
+		"invalid_minutes":           "Введите корректное время в минутах.",
+		"confirm_one_shot":          "Запустить таймер \"%s\" на %v минут?",
+		"confirm_schedule":          "Запустить таймер \"%s\" по расписанию \"%s\"?",
+		"btn_confirm":               "Запустить",
+		"timer_setup_error":         "Ошибка! Сначала настройте таймер.",
+		"schedule_setup_error":      "Ошибка! Сначала настройте расписание.",
+		"timer_started":             "Таймер \"%s\" запущен на %v минут.",
+		"btn_stop":                  "Остановить таймер",
+		"schedule_started":          "Таймер \"%s\" будет срабатывать по расписанию \"%s\".",
+		"btn_cancel":                "Отменить",
+		"job_fired":                 "⏰ Таймер \"%s\" сработал по расписанию.",
+		"job_not_found":             "Таймер не найден.",
+		"schedule_cancelled":        "Расписание отменено.",
+		"timer_not_found":           "Таймер не найден или уже остановлен.",
+		"timer_stopped":             "Таймер \"%s\" остановлен.",
+		"timer_stopped_auto_suffix": " ⏳ Время истекло!",
+		"no_active_timers":          "Нет активных таймеров.",
+		"active_timers_header":      "Активные таймеры:",
+		"timer_list_label":          "%s (%v мин)",
+		"logs_empty":                "🔍 Логи пусты.",
+		"logs_failed":               "🔍 Не удалось загрузить логи.",
+		"logs_header":               "📜 Логи таймеров:\n\n%s",
+		"log_name_label":            "Название",
+		"log_action_start":          "Запуск",
+		"log_action_stop":           "Остановлен",
+		"log_action_schedule_start": "Запуск расписания",
+		"log_action_schedule_fire":  "Сработал по расписанию",
+		"prenotify_message":         "⏳ Осталось %d мин. до конца таймера \"%s\"",
+		"settings_header":           "Настройки:",
+		"settings_lang_label":       "Язык: %s",
+		"settings_sound_label":      "Звук: %s",
+		"settings_prenotify_label":  "Уведомление за %d мин: %s",
+		"on":                        "вкл",
+		"off":                       "выкл",
+	},
+	"en": {
+		"main_menu":                 "Choose an action:",
+		"btn_start_timer":           "Start timer",
+		"btn_list_timers":           "My timers",
+		"btn_show_logs":             "Show logs",
+		"btn_settings":              "Settings",
+		"btn_presets":               "Presets",
+		"use_buttons":               "Use the buttons to work with timers.",
+		"presets_empty":             "You have no presets yet. Create one: /preset <name> <minutes>",
+		"presets_header":            "Your presets:",
+		"preset_usage":              "Usage: /preset <name> <minutes> | /preset list | /preset del <name>",
+		"preset_saved":              "Preset \"%s\" saved (%d min).",
+		"preset_deleted":            "Preset \"%s\" deleted.",
+		"preset_not_found":          "Preset not found.",
+		"preset_list_entry":         "\n%s — %d min",
+		"preset_button_label":       "%s (%d min)",
+		"enter_timer_name":          "Enter the timer name:",
+		"enter_minutes_or_schedule": "Enter the time in minutes, or choose a recurring schedule:",
+		"btn_daily":                 "Daily",
+		"btn_cron":                  "Cron",
+		"enter_daily_time":          "Enter the daily run time as HH:MM:",
+		"enter_cron_expr":           "Enter a cron expression (minute hour day month weekday):",
+		"invalid_daily_time":        "Enter a valid time as HH:MM.",
+		"invalid_cron":              "Enter a valid cron expression.",
+		"invalid_minutes":           "Enter a valid number of minutes.",
+		"confirm_one_shot":          "Start the \"%s\" timer for %v minutes?",
+		"confirm_schedule":          "Start the \"%s\" timer on schedule \"%s\"?",
+		"btn_confirm":               "Start",
+		"timer_setup_error":         "Error! Set up the timer first.",
+		"schedule_setup_error":      "Error! Set up the schedule first.",
+		"timer_started":             "Timer \"%s\" started for %v minutes.",
+		"btn_stop":                  "Stop timer",
+		"schedule_started":          "Timer \"%s\" will fire on schedule \"%s\".",
+		"btn_cancel":                "Cancel",
+		"job_fired":                 "⏰ Timer \"%s\" fired on schedule.",
+		"job_not_found":             "Timer not found.",
+		"schedule_cancelled":        "Schedule cancelled.",
+		"timer_not_found":           "Timer not found or already stopped.",
+		"timer_stopped":             "Timer \"%s\" stopped.",
+		"timer_stopped_auto_suffix": " ⏳ Time's up!",
+		"no_active_timers":          "No active timers.",
+		"active_timers_header":      "Active timers:",
+		"timer_list_label":          "%s (%v min)",
+		"logs_empty":                "🔍 Log is empty.",
+		"logs_failed":               "🔍 Failed to load the log.",
+		"logs_header":               "📜 Timer log:\n\n%s",
+		"log_name_label":            "Name",
+		"log_action_start":          "Start",
+		"log_action_stop":           "Stopped",
+		"log_action_schedule_start": "Schedule started",
+		"log_action_schedule_fire":  "Fired on schedule",
+		"prenotify_message":         "⏳ %d min left on timer \"%s\"",
+		"settings_header":           "Settings:",
+		"settings_lang_label":       "Language: %s",
+		"settings_sound_label":      "Sound: %s",
+		"settings_prenotify_label":  "%d min pre-notify: %s",
+		"on":                        "on",
+		"off":                       "off",
+	},
+}
+
+// T returns the message for key in lang, falling back to DefaultLang if
+// lang or the key isn't known.
+func T(lang, key string) string {
+	if msgs, ok := messages[lang]; ok {
+		if s, ok := msgs[key]; ok {
+			return s
+		}
+	}
+	return messages[DefaultLang][key]
+}
@@ -1,30 +1,115 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"timer_bot/i18n"
+	"timer_bot/scheduler"
+	"timer_bot/storage"
 )
 
+// defaultWorkers is used when WORKERS isn't set or isn't a positive
+// number.
+const defaultWorkers = 4
+
 type Timer struct {
+	ID        string
 	Name      string
 	StartTime time.Time
 	Duration  time.Duration
 	StopTime  *time.Time
+
+	// Schedule, NextFire and Repeat describe a recurring timer. While a
+	// timer is still being configured, Schedule additionally doubles as
+	// the setup stage marker (see awaitingDaily/awaitingCron below).
+	Schedule string
+	NextFire time.Time
+	Repeat   bool
+}
+
+// Sentinel Schedule values used only while a recurring timer is being
+// configured, before a real cron expression has been entered.
+const (
+	awaitingDaily = "awaiting_daily"
+	awaitingCron  = "awaiting_cron"
+)
+
+// Log action keys recorded via appendLog. They are language-neutral so
+// sendLogs can translate them to the chat's language at render time
+// instead of baking one language into storage.
+const (
+	actionStart         = "start"
+	actionStop          = "stop"
+	actionScheduleStart = "schedule_start"
+	actionScheduleFire  = "schedule_fire"
+)
+
+// chatTimers holds every timer running for a single chat, guarded by its
+// own mutex so operations on one chat never block another.
+type chatTimers struct {
+	mu     sync.Mutex
+	timers map[string]*Timer
 }
 
 var (
-	bot          *tgbotapi.BotAPI
-	activeTimers = make(map[int64]*Timer)
-	mu           sync.Mutex
+	bot *tgbotapi.BotAPI
+
+	// activeTimers is keyed by chatID -> chatID's own timer collection,
+	// so a chat can run several named timers at once.
+	activeTimers = make(map[int64]*chatTimers)
+	timersMu     sync.Mutex
+
+	// pendingSetup tracks the timer being configured (name/duration) for
+	// a chat before it is promoted into activeTimers.
+	pendingSetup   = make(map[int64]*Timer)
+	pendingSetupMu sync.Mutex
+
+	jobScheduler = scheduler.New(handleRecurringFire)
+
+	// appCtx is cancelled on SIGINT/SIGTERM; every goroutine started for
+	// a running timer watches it so a shutdown doesn't leave orphans.
+	appCtx = context.Background()
+
+	// settingsCache avoids a DB round trip on every message; it is
+	// filled lazily and kept in sync by saveSettings.
+	settingsCache   = make(map[int64]*storage.UserSettings)
+	settingsCacheMu sync.Mutex
+
+	// chatDispatchers routes every update for a given chat through a
+	// single goroutine, so the worker pool still runs chats in parallel
+	// while updates within one chat (e.g. the timer-name message
+	// immediately followed by the minutes message) are always handled
+	// one at a time, in order, never touching pendingSetup/settingsCache
+	// entries concurrently.
+	chatDispatchers   = make(map[int64]*chatDispatcher)
+	chatDispatchersMu sync.Mutex
+	dispatchWg        sync.WaitGroup
 )
 
-const logFile = "timers.log"
+// chatDispatcher owns the single goroutine responsible for every update
+// belonging to one chat.
+type chatDispatcher struct {
+	ch chan tgbotapi.Update
+}
+
+const dbFile = "timers.db"
+
+// logsPageSize caps how many entries /logs shows at once.
+const logsPageSize = 20
 
 func main() {
 	err := godotenv.Load()
@@ -37,6 +122,10 @@ func main() {
 		log.Fatal("Токен бота не найден")
 	}
 
+	if err := storage.Open(dbFile); err != nil {
+		log.Fatal("Ошибка открытия базы данных:", err)
+	}
+
 	bot, err = tgbotapi.NewBotAPI(botToken)
 	if err != nil {
 		log.Fatal(err)
@@ -44,11 +133,119 @@ func main() {
 
 	log.Println("Бот запущен:", bot.Self.UserName)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	appCtx = ctx
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Println("Получен сигнал завершения, останавливаемся...")
+		cancel()
+	}()
+
+	jobScheduler.Start(ctx)
+	resumeActiveTimers()
+
+	workers := workerCount()
+	updateCh := make(chan tgbotapi.Update, workers*4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			processUpdates(updateCh)
+		}()
+	}
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 	updates := bot.GetUpdatesChan(u)
 
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case update := <-updates:
+			updateCh <- update
+		}
+	}
+
+	bot.StopReceivingUpdates()
+	close(updateCh)
+	wg.Wait()
+
+	chatDispatchersMu.Lock()
+	for _, d := range chatDispatchers {
+		close(d.ch)
+	}
+	chatDispatchersMu.Unlock()
+	dispatchWg.Wait()
+
+	log.Println("Бот остановлен.")
+}
+
+// workerCount reads the WORKERS env var, falling back to defaultWorkers
+// if it's unset or not a positive number.
+func workerCount() int {
+	if v := os.Getenv("WORKERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultWorkers
+}
+
+// processUpdates is run by each worker in the pool, so a slow bot.Send
+// for one chat no longer blocks every other chat. It only routes updates
+// to their chat's dispatcher; the actual handling happens in
+// runChatDispatcher, one chat at a time.
+func processUpdates(updates <-chan tgbotapi.Update) {
 	for update := range updates {
+		chatID, ok := updateChatID(update)
+		if !ok {
+			continue
+		}
+		dispatchToChat(chatID, update)
+	}
+}
+
+// updateChatID extracts the chat an update belongs to, if any.
+func updateChatID(update tgbotapi.Update) (int64, bool) {
+	if update.CallbackQuery != nil {
+		return update.CallbackQuery.Message.Chat.ID, true
+	}
+	if update.Message != nil {
+		return update.Message.Chat.ID, true
+	}
+	return 0, false
+}
+
+// dispatchToChat hands update to the single goroutine that owns chatID,
+// starting one if this is the chat's first update, so every chat's
+// updates are handled strictly in order and never concurrently with
+// each other.
+func dispatchToChat(chatID int64, update tgbotapi.Update) {
+	chatDispatchersMu.Lock()
+	d, ok := chatDispatchers[chatID]
+	if !ok {
+		d = &chatDispatcher{ch: make(chan tgbotapi.Update, 8)}
+		chatDispatchers[chatID] = d
+		dispatchWg.Add(1)
+		go runChatDispatcher(d)
+	}
+	chatDispatchersMu.Unlock()
+	d.ch <- update
+}
+
+// runChatDispatcher is the single goroutine that handles every update for
+// one chat, one at a time, in the order they arrived.
+func runChatDispatcher(d *chatDispatcher) {
+	defer dispatchWg.Done()
+	for update := range d.ch {
 		if update.CallbackQuery != nil {
 			handleCallback(update.CallbackQuery)
 		} else if update.Message != nil {
@@ -60,28 +257,56 @@ func main() {
 func handleMessage(msg *tgbotapi.Message) {
 	chatID := msg.Chat.ID
 
+	if strings.HasPrefix(msg.Text, "/cancel ") {
+		id := strings.TrimSpace(strings.TrimPrefix(msg.Text, "/cancel "))
+		cancelJob(chatID, id)
+		return
+	}
+
+	if msg.Text == "/preset" || strings.HasPrefix(msg.Text, "/preset ") {
+		handlePresetCommand(chatID, strings.TrimSpace(strings.TrimPrefix(msg.Text, "/preset")))
+		return
+	}
+
 	switch msg.Text {
 	case "/start":
 		sendMainMenu(chatID)
 	case "/logs":
 		sendLogs(chatID)
+	case "/list":
+		sendTimerList(chatID)
+	case "/settings":
+		sendSettingsMenu(chatID)
 	default:
-		if timer, exists := activeTimers[chatID]; exists && timer.Duration == 0 {
+		pendingSetupMu.Lock()
+		_, exists := pendingSetup[chatID]
+		pendingSetupMu.Unlock()
+		if exists {
 			handleTimerSetup(chatID, msg.Text)
 		} else {
-			bot.Send(tgbotapi.NewMessage(chatID, "Используй кнопки для работы с таймерами."))
+			bot.Send(tgbotapi.NewMessage(chatID, i18n.T(chatLang(chatID), "use_buttons")))
 		}
 	}
 }
 
 func sendMainMenu(chatID int64) {
-	msg := tgbotapi.NewMessage(chatID, "Выберите действие:")
+	lang := chatLang(chatID)
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "main_menu"))
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Старт таймера", "start_timer"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_start_timer"), "start_timer"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_list_timers"), "list_timers"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_show_logs"), "show_logs"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_presets"), "list_presets"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Показать логи", "show_logs"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_settings"), "open_settings"),
 		),
 	)
 	bot.Send(msg)
@@ -91,133 +316,782 @@ func handleCallback(query *tgbotapi.CallbackQuery) {
 	chatID := query.Message.Chat.ID
 	data := query.Data
 
-	switch data {
-	case "start_timer":
-		bot.Send(tgbotapi.NewMessage(chatID, "Введите название таймера:"))
-		activeTimers[chatID] = &Timer{}
-	case "show_logs":
+	lang := chatLang(chatID)
+
+	switch {
+	case data == "start_timer":
+		pendingSetupMu.Lock()
+		pendingSetup[chatID] = &Timer{}
+		pendingSetupMu.Unlock()
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "enter_timer_name")))
+	case data == "show_logs":
 		sendLogs(chatID)
-	case "confirm_timer":
+	case data == "list_timers":
+		sendTimerList(chatID)
+	case data == "open_settings":
+		sendSettingsMenu(chatID)
+	case data == "list_presets":
+		sendPresetKeyboard(chatID)
+	case strings.HasPrefix(data, "preset_use:"):
+		name := strings.TrimPrefix(data, "preset_use:")
+		usePreset(chatID, name)
+	case data == "schedule_daily":
+		setAwaitingSchedule(chatID, awaitingDaily)
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "enter_daily_time")))
+	case data == "schedule_cron":
+		setAwaitingSchedule(chatID, awaitingCron)
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "enter_cron_expr")))
+	case data == "confirm_timer":
 		startTimer(chatID)
-	case "stop_timer":
-		stopTimer(chatID, false)
+	case data == "confirm_schedule":
+		startRecurringTimer(chatID)
+	case data == "opt_lang":
+		s := getSettings(chatID)
+		if s.Language == "en" {
+			s.Language = "ru"
+		} else {
+			s.Language = "en"
+		}
+		saveSettings(s)
+		sendSettingsMenu(chatID)
+	case data == "opt_sound":
+		s := getSettings(chatID)
+		s.SoundEmoji = !s.SoundEmoji
+		saveSettings(s)
+		sendSettingsMenu(chatID)
+	case data == "opt_prenotify_5":
+		s := getSettings(chatID)
+		togglePreNotify(s, 5)
+		saveSettings(s)
+		sendSettingsMenu(chatID)
+	case data == "opt_prenotify_60":
+		s := getSettings(chatID)
+		togglePreNotify(s, 60)
+		saveSettings(s)
+		sendSettingsMenu(chatID)
+	case strings.HasPrefix(data, "stop_timer:"):
+		id := strings.TrimPrefix(data, "stop_timer:")
+		stopTimer(chatID, id, false)
+	case strings.HasPrefix(data, "cancel_job:"):
+		id := strings.TrimPrefix(data, "cancel_job:")
+		cancelJob(chatID, id)
+	}
+}
+
+// setAwaitingSchedule marks the chat's pending timer as waiting for a
+// daily time or cron expression, parsed on the next text message.
+func setAwaitingSchedule(chatID int64, stage string) {
+	pendingSetupMu.Lock()
+	if timer, exists := pendingSetup[chatID]; exists {
+		timer.Schedule = stage
 	}
+	pendingSetupMu.Unlock()
 }
 
 func handleTimerSetup(chatID int64, input string) {
-	timer := activeTimers[chatID]
+	pendingSetupMu.Lock()
+	timer := pendingSetup[chatID]
+	pendingSetupMu.Unlock()
+
+	lang := chatLang(chatID)
 
 	if timer.Name == "" {
 		timer.Name = input
-		bot.Send(tgbotapi.NewMessage(chatID, "Введите время в минутах:"))
+		msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "enter_minutes_or_schedule"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_daily"), "schedule_daily"),
+				tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_cron"), "schedule_cron"),
+			),
+		)
+		bot.Send(msg)
+		return
+	}
+
+	switch timer.Schedule {
+	case awaitingDaily:
+		handleDailyScheduleInput(chatID, timer, input)
+		return
+	case awaitingCron:
+		handleCronScheduleInput(chatID, timer, input)
 		return
 	}
 
 	duration, err := time.ParseDuration(input + "m")
 	if err != nil {
-		bot.Send(tgbotapi.NewMessage(chatID, "Введите корректное время в минутах."))
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "invalid_minutes")))
 		return
 	}
 
+	confirmOneShot(chatID, timer, duration)
+}
+
+// confirmOneShot records duration on the pending timer and asks the user
+// to confirm starting it, whether duration came from typed minutes or a
+// preset.
+func confirmOneShot(chatID int64, timer *Timer, duration time.Duration) {
+	lang := chatLang(chatID)
+
 	timer.Duration = duration
-	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Запустить таймер \"%s\" на %v минут?", timer.Name, duration.Minutes()))
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(i18n.T(lang, "confirm_one_shot"), timer.Name, duration.Minutes()))
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Запустить", "confirm_timer"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_confirm"), "confirm_timer"),
+		),
+	)
+	bot.Send(msg)
+}
+
+// handleDailyScheduleInput parses a ЧЧ:ММ time into a daily cron
+// expression and shows the confirmation step.
+func handleDailyScheduleInput(chatID int64, timer *Timer, input string) {
+	lang := chatLang(chatID)
+	t, err := time.Parse("15:04", input)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "invalid_daily_time")))
+		return
+	}
+	cronExpr := fmt.Sprintf("%d %d * * *", t.Minute(), t.Hour())
+	confirmSchedule(chatID, timer, cronExpr)
+}
+
+// handleCronScheduleInput validates a raw cron expression and shows the
+// confirmation step.
+func handleCronScheduleInput(chatID int64, timer *Timer, input string) {
+	if _, err := scheduler.NextFire(input, time.Now()); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(chatLang(chatID), "invalid_cron")))
+		return
+	}
+	confirmSchedule(chatID, timer, input)
+}
+
+// confirmSchedule records the resolved cron expression on the pending
+// timer and asks the user to confirm starting the recurring job.
+func confirmSchedule(chatID int64, timer *Timer, cronExpr string) {
+	lang := chatLang(chatID)
+
+	next, err := scheduler.NextFire(cronExpr, time.Now())
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "invalid_cron")))
+		return
+	}
+
+	timer.Schedule = cronExpr
+	timer.Repeat = true
+	timer.NextFire = next
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(i18n.T(lang, "confirm_schedule"), timer.Name, cronExpr))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_confirm"), "confirm_schedule"),
 		),
 	)
 	bot.Send(msg)
 }
 
 func startTimer(chatID int64) {
-	mu.Lock()
-	timer, exists := activeTimers[chatID]
-	mu.Unlock()
+	pendingSetupMu.Lock()
+	timer, exists := pendingSetup[chatID]
+	if exists {
+		delete(pendingSetup, chatID)
+	}
+	pendingSetupMu.Unlock()
+	lang := chatLang(chatID)
 	if !exists || timer.Name == "" || timer.Duration == 0 {
-		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка! Сначала настройте таймер."))
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "timer_setup_error")))
+		return
+	}
+
+	timer.ID = generateTimerID(chatID)
+	timer.StartTime = time.Now()
+
+	ct := chatTimersFor(chatID)
+	ct.mu.Lock()
+	ct.timers[timer.ID] = timer
+	ct.mu.Unlock()
+
+	if err := storage.SaveTimer(&storage.Timer{
+		ID:        timer.ID,
+		ChatID:    chatID,
+		Name:      timer.Name,
+		StartTime: timer.StartTime,
+		Duration:  timer.Duration,
+		Status:    storage.StatusRunning,
+	}); err != nil {
+		log.Println("Ошибка сохранения таймера:", err)
+	}
+	appendLog(chatID, actionStart, timer.Name)
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(i18n.T(lang, "timer_started"), timer.Name, timer.Duration.Minutes()))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_stop"), "stop_timer:"+timer.ID),
+		),
+	)
+	bot.Send(msg)
+
+	scheduleStop(chatID, timer.ID, timer.Duration)
+	schedulePreNotifies(chatID, timer.ID, timer.Name, timer.Duration, lang, parsePreNotify(getSettings(chatID).PreNotify))
+}
+
+// startRecurringTimer promotes a confirmed recurring pending timer into a
+// persisted, scheduled job.
+func startRecurringTimer(chatID int64) {
+	pendingSetupMu.Lock()
+	timer, exists := pendingSetup[chatID]
+	if exists {
+		delete(pendingSetup, chatID)
+	}
+	pendingSetupMu.Unlock()
+	lang := chatLang(chatID)
+	if !exists || timer.Name == "" || timer.Schedule == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "schedule_setup_error")))
 		return
 	}
 
+	timer.ID = generateTimerID(chatID)
 	timer.StartTime = time.Now()
-	logTimer(timer, "Запуск")
 
-	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Таймер \"%s\" запущен на %v минут.", timer.Name, timer.Duration.Minutes()))
+	ct := chatTimersFor(chatID)
+	ct.mu.Lock()
+	ct.timers[timer.ID] = timer
+	ct.mu.Unlock()
+
+	if err := storage.SaveTimer(&storage.Timer{
+		ID:        timer.ID,
+		ChatID:    chatID,
+		Name:      timer.Name,
+		StartTime: timer.StartTime,
+		Schedule:  timer.Schedule,
+		NextFire:  timer.NextFire,
+		Repeat:    true,
+		Status:    storage.StatusRunning,
+	}); err != nil {
+		log.Println("Ошибка сохранения расписания:", err)
+	}
+	appendLog(chatID, actionScheduleStart, timer.Name)
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(i18n.T(lang, "schedule_started"), timer.Name, timer.Schedule))
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Остановить таймер", "stop_timer"),
+			tgbotapi.NewInlineKeyboardButtonData(i18n.T(lang, "btn_cancel"), "cancel_job:"+timer.ID),
 		),
 	)
 	bot.Send(msg)
 
-	go func(chatID int64, duration time.Duration) {
-		time.Sleep(duration)
-		mu.Lock()
-		_, exists := activeTimers[chatID]
-		mu.Unlock()
+	jobScheduler.Add(scheduler.Job{
+		ID:       timer.ID,
+		ChatID:   chatID,
+		Name:     timer.Name,
+		Schedule: timer.Schedule,
+		NextFire: timer.NextFire,
+		Repeat:   true,
+	})
+}
+
+// handleRecurringFire is called by the scheduler every time a recurring
+// job comes due.
+func handleRecurringFire(job scheduler.Job) {
+	lang := chatLang(job.ChatID)
+	bot.Send(tgbotapi.NewMessage(job.ChatID, fmt.Sprintf(i18n.T(lang, "job_fired"), job.Name)))
+	appendLog(job.ChatID, actionScheduleFire, job.Name)
+
+	ct := chatTimersFor(job.ChatID)
+	ct.mu.Lock()
+	if timer, ok := ct.timers[job.ID]; ok {
+		timer.NextFire = job.NextFire
+	}
+	ct.mu.Unlock()
+
+	if err := storage.UpdateNextFire(job.ID, job.NextFire); err != nil {
+		log.Println("Ошибка обновления расписания:", err)
+	}
+}
+
+// cancelJob removes a recurring job, used by both /cancel and the
+// "Отменить" inline button.
+func cancelJob(chatID int64, id string) {
+	lang := chatLang(chatID)
+
+	ct := chatTimersFor(chatID)
+	ct.mu.Lock()
+	_, exists := ct.timers[id]
+	delete(ct.timers, id)
+	ct.mu.Unlock()
+	if !exists {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "job_not_found")))
+		return
+	}
+
+	jobScheduler.Remove(id)
+	if err := storage.MarkStopped(id, false); err != nil {
+		log.Println("Ошибка отмены расписания:", err)
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "schedule_cancelled")))
+}
+
+// scheduleStop waits for duration and then auto-stops the timer if it is
+// still running, either right after startTimer or after being
+// rescheduled on startup by resumeActiveTimers.
+func scheduleStop(chatID int64, id string, duration time.Duration) {
+	go func() {
+		select {
+		case <-time.After(duration):
+		case <-appCtx.Done():
+			return
+		}
+
+		ct := chatTimersFor(chatID)
+		ct.mu.Lock()
+		_, exists := ct.timers[id]
+		ct.mu.Unlock()
 		if exists {
-			stopTimer(chatID, true)
+			stopTimer(chatID, id, true)
+		}
+	}()
+}
+
+// resumeActiveTimers reloads every timer still marked as running in the
+// store, firing expired ones immediately and rescheduling the rest.
+func resumeActiveTimers() {
+	rows, err := storage.LoadActive()
+	if err != nil {
+		log.Println("Ошибка загрузки активных таймеров:", err)
+		return
+	}
+
+	for _, row := range rows {
+		timer := &Timer{
+			ID:        row.ID,
+			Name:      row.Name,
+			StartTime: row.StartTime,
+			Duration:  row.Duration,
+			Schedule:  row.Schedule,
+			NextFire:  row.NextFire,
+			Repeat:    row.Repeat,
+		}
+
+		ct := chatTimersFor(row.ChatID)
+		ct.mu.Lock()
+		ct.timers[timer.ID] = timer
+		ct.mu.Unlock()
+
+		if row.Repeat {
+			jobScheduler.Add(scheduler.Job{
+				ID:       timer.ID,
+				ChatID:   row.ChatID,
+				Name:     timer.Name,
+				Schedule: timer.Schedule,
+				NextFire: timer.NextFire,
+				Repeat:   true,
+			})
+			continue
+		}
+
+		remaining := row.StartTime.Add(row.Duration).Sub(time.Now())
+		if remaining <= 0 {
+			stopTimer(row.ChatID, timer.ID, true)
+			continue
 		}
-	}(chatID, timer.Duration)
+		scheduleStop(row.ChatID, timer.ID, remaining)
+
+		s := getSettings(row.ChatID)
+		schedulePreNotifies(row.ChatID, timer.ID, timer.Name, remaining, s.Language, parsePreNotify(s.PreNotify))
+	}
 }
 
-func stopTimer(chatID int64, auto bool) {
-	mu.Lock()
-	timer, exists := activeTimers[chatID]
+func stopTimer(chatID int64, id string, auto bool) {
+	lang := chatLang(chatID)
+
+	ct := chatTimersFor(chatID)
+	ct.mu.Lock()
+	timer, exists := ct.timers[id]
 	if !exists {
-		mu.Unlock()
-		bot.Send(tgbotapi.NewMessage(chatID, "Нет активного таймера."))
+		ct.mu.Unlock()
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "timer_not_found")))
 		return
 	}
 
 	stopTime := time.Now()
 	timer.StopTime = &stopTime
-	logTimer(timer, "Остановлен")
-	delete(activeTimers, chatID)
-	mu.Unlock()
+	delete(ct.timers, id)
+	ct.mu.Unlock()
+
+	if err := storage.MarkStopped(id, auto); err != nil {
+		log.Println("Ошибка обновления таймера:", err)
+	}
+	appendLog(chatID, actionStop, timer.Name)
 
-	message := fmt.Sprintf("Таймер \"%s\" остановлен.", timer.Name)
+	message := fmt.Sprintf(i18n.T(lang, "timer_stopped"), timer.Name)
 	if auto {
-		message += " ⏳ Время истекло!"
+		message += i18n.T(lang, "timer_stopped_auto_suffix")
 	}
 	bot.Send(tgbotapi.NewMessage(chatID, message))
 }
 
-func logTimer(timer *Timer, action string) {
-	entry := fmt.Sprintf("%s | Название: %s | Начало: %s | Окончание: %s\n",
-		action,
-		timer.Name,
-		timer.StartTime.Format("2006-01-02 15:04:05"),
-		func() string {
-			if timer.StopTime != nil {
-				return timer.StopTime.Format("2006-01-02 15:04:05")
-			}
-			return "В процессе"
-		}(),
+// chatTimersFor returns the timer collection for chatID, creating it on
+// first use.
+func chatTimersFor(chatID int64) *chatTimers {
+	timersMu.Lock()
+	defer timersMu.Unlock()
+
+	ct, exists := activeTimers[chatID]
+	if !exists {
+		ct = &chatTimers{timers: make(map[string]*Timer)}
+		activeTimers[chatID] = ct
+	}
+	return ct
+}
+
+// getSettings returns chatID's settings, loading defaults from storage
+// (or creating them) on first use.
+func getSettings(chatID int64) *storage.UserSettings {
+	settingsCacheMu.Lock()
+	if s, ok := settingsCache[chatID]; ok {
+		settingsCacheMu.Unlock()
+		return s
+	}
+	settingsCacheMu.Unlock()
+
+	s, err := storage.LoadSettings(chatID)
+	if err != nil {
+		log.Println("Ошибка загрузки настроек:", err)
+	}
+	if s == nil {
+		s = &storage.UserSettings{
+			ChatID:     chatID,
+			Language:   i18n.DefaultLang,
+			TimeFormat: "15:04",
+			SoundEmoji: true,
+		}
+	}
+
+	settingsCacheMu.Lock()
+	settingsCache[chatID] = s
+	settingsCacheMu.Unlock()
+	return s
+}
+
+// saveSettings persists s and refreshes the cache.
+func saveSettings(s *storage.UserSettings) {
+	settingsCacheMu.Lock()
+	settingsCache[s.ChatID] = s
+	settingsCacheMu.Unlock()
+
+	if err := storage.SaveSettings(s); err != nil {
+		log.Println("Ошибка сохранения настроек:", err)
+	}
+}
+
+// chatLang is a shorthand for the i18n language of a chat's settings.
+func chatLang(chatID int64) string {
+	return getSettings(chatID).Language
+}
+
+// parsePreNotify turns the settings' comma-separated minute offsets into
+// a slice, e.g. "5,60" -> [5 60].
+func parsePreNotify(s string) []int {
+	if s == "" {
+		return nil
+	}
+	var minutes []int
+	for _, part := range strings.Split(s, ",") {
+		if n, err := strconv.Atoi(part); err == nil {
+			minutes = append(minutes, n)
+		}
+	}
+	return minutes
+}
+
+// togglePreNotify adds or removes minutes from s.PreNotify.
+func togglePreNotify(s *storage.UserSettings, minutes int) {
+	offsets := parsePreNotify(s.PreNotify)
+	idx := -1
+	for i, m := range offsets {
+		if m == minutes {
+			idx = i
+			break
+		}
+	}
+	if idx >= 0 {
+		offsets = append(offsets[:idx], offsets[idx+1:]...)
+	} else {
+		offsets = append(offsets, minutes)
+	}
+	sort.Ints(offsets)
+
+	parts := make([]string, len(offsets))
+	for i, m := range offsets {
+		parts[i] = strconv.Itoa(m)
+	}
+	s.PreNotify = strings.Join(parts, ",")
+}
+
+func onOffLabel(lang string, enabled bool) string {
+	if enabled {
+		return i18n.T(lang, "on")
+	}
+	return i18n.T(lang, "off")
+}
+
+func hasPreNotify(offsets []int, minutes int) bool {
+	for _, m := range offsets {
+		if m == minutes {
+			return true
+		}
+	}
+	return false
+}
+
+// sendSettingsMenu renders the /settings toggle keyboard for chatID.
+func sendSettingsMenu(chatID int64) {
+	s := getSettings(chatID)
+	lang := s.Language
+	offsets := parsePreNotify(s.PreNotify)
+
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "settings_header"))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(i18n.T(lang, "settings_lang_label"), strings.ToUpper(s.Language)), "opt_lang"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(i18n.T(lang, "settings_sound_label"), onOffLabel(lang, s.SoundEmoji)), "opt_sound"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(i18n.T(lang, "settings_prenotify_label"), 5, onOffLabel(lang, hasPreNotify(offsets, 5))), "opt_prenotify_5"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(i18n.T(lang, "settings_prenotify_label"), 60, onOffLabel(lang, hasPreNotify(offsets, 60))), "opt_prenotify_60"),
+		),
 	)
+	bot.Send(msg)
+}
+
+// schedulePreNotifies schedules an extra "time's almost up" ping for each
+// configured offset, alongside the timer's normal expiry goroutine.
+func schedulePreNotifies(chatID int64, id, name string, duration time.Duration, lang string, offsets []int) {
+	for _, offset := range offsets {
+		wait := duration - time.Duration(offset)*time.Minute
+		if wait <= 0 {
+			continue
+		}
+		go func(offset int, wait time.Duration) {
+			select {
+			case <-time.After(wait):
+			case <-appCtx.Done():
+				return
+			}
+
+			ct := chatTimersFor(chatID)
+			ct.mu.Lock()
+			_, exists := ct.timers[id]
+			ct.mu.Unlock()
+			if exists {
+				bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(i18n.T(lang, "prenotify_message"), offset, name)))
+			}
+		}(offset, wait)
+	}
+}
+
+// handlePresetCommand implements "/preset <name> <minutes>", "/preset
+// list" and "/preset del <name>".
+func handlePresetCommand(chatID int64, args string) {
+	lang := chatLang(chatID)
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "preset_usage")))
+		return
+	}
+
+	switch fields[0] {
+	case "list":
+		sendPresetText(chatID)
+	case "del":
+		if len(fields) < 2 {
+			bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "preset_usage")))
+			return
+		}
+		deletePreset(chatID, fields[1])
+	default:
+		if len(fields) < 2 {
+			bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "preset_usage")))
+			return
+		}
+		minutes, err := strconv.Atoi(fields[1])
+		if err != nil || minutes <= 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "invalid_minutes")))
+			return
+		}
+		savePreset(chatID, fields[0], minutes)
+	}
+}
 
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+func savePreset(chatID int64, name string, minutes int) {
+	lang := chatLang(chatID)
+	if err := storage.SavePreset(chatID, name, minutes); err != nil {
+		log.Println("Ошибка сохранения пресета:", err)
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(i18n.T(lang, "preset_saved"), name, minutes)))
+}
+
+func deletePreset(chatID int64, name string) {
+	lang := chatLang(chatID)
+	deleted, err := storage.DeletePreset(chatID, name)
 	if err != nil {
-		log.Println("Ошибка записи в лог:", err)
+		log.Println("Ошибка удаления пресета:", err)
 		return
 	}
-	defer file.Close()
+	if !deleted {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "preset_not_found")))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(i18n.T(lang, "preset_deleted"), name)))
+}
 
-	_, err = file.WriteString(entry)
+// sendPresetText lists a chat's presets as plain text, used by
+// "/preset list".
+func sendPresetText(chatID int64) {
+	lang := chatLang(chatID)
+	presets, err := storage.ListPresets(chatID)
 	if err != nil {
-		log.Println("Ошибка при сохранении логов:", err)
+		log.Println("Ошибка загрузки пресетов:", err)
+		return
 	}
+	if len(presets) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "presets_empty")))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.T(lang, "presets_header"))
+	for _, p := range presets {
+		fmt.Fprintf(&b, i18n.T(lang, "preset_list_entry"), p.Name, p.Minutes)
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
 }
 
-func sendLogs(chatID int64) {
-	data, err := os.ReadFile(logFile)
-	if err != nil || len(data) == 0 {
-		bot.Send(tgbotapi.NewMessage(chatID, "🔍 Логи пусты."))
+// sendPresetKeyboard renders a chat's presets as inline buttons, used by
+// the main menu's "Presets" button.
+func sendPresetKeyboard(chatID int64) {
+	lang := chatLang(chatID)
+	presets, err := storage.ListPresets(chatID)
+	if err != nil {
+		log.Println("Ошибка загрузки пресетов:", err)
+		return
+	}
+	if len(presets) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "presets_empty")))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "presets_header"))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, p := range presets {
+		label := fmt.Sprintf(i18n.T(lang, "preset_button_label"), p.Name, p.Minutes)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "preset_use:"+p.Name),
+		))
+	}
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	bot.Send(msg)
+}
+
+// usePreset jumps straight from a preset tap to the one-shot confirmation
+// step, skipping the name/duration prompts.
+func usePreset(chatID int64, name string) {
+	lang := chatLang(chatID)
+	preset, err := storage.GetPreset(chatID, name)
+	if err != nil {
+		log.Println("Ошибка загрузки пресета:", err)
+		return
+	}
+	if preset == nil {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "preset_not_found")))
 		return
 	}
 
-	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("📜 Логи таймеров:\n\n%s", string(data))))
+	timer := &Timer{Name: preset.Name}
+	pendingSetupMu.Lock()
+	pendingSetup[chatID] = timer
+	pendingSetupMu.Unlock()
+
+	confirmOneShot(chatID, timer, time.Duration(preset.Minutes)*time.Minute)
+}
+
+// generateTimerID produces a globally unique id for a newly started
+// timer. It's prefixed with chatID so two chats can never collide even
+// if they start a timer on the exact same tick, and suffixed with a
+// UUID since Timer.ID is the sole primary key in storage.
+func generateTimerID(chatID int64) string {
+	return fmt.Sprintf("%d-%s", chatID, uuid.NewString())
 }
 
+func sendTimerList(chatID int64) {
+	lang := chatLang(chatID)
+
+	ct := chatTimersFor(chatID)
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+
+	if len(ct.timers) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "no_active_timers")))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, i18n.T(lang, "active_timers_header"))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, timer := range ct.timers {
+		if timer.Repeat {
+			label := fmt.Sprintf("%s (%s)", timer.Name, timer.Schedule)
+			rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(label, "cancel_job:"+timer.ID),
+			))
+			continue
+		}
+		label := fmt.Sprintf(i18n.T(lang, "timer_list_label"), timer.Name, timer.Duration.Minutes())
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, "stop_timer:"+timer.ID),
+		))
+	}
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	bot.Send(msg)
+}
+
+// appendLog records a timer action in the store, logging but not
+// surfacing a storage failure to the chat.
+func appendLog(chatID int64, action, name string) {
+	if err := storage.AppendLog(chatID, action, name); err != nil {
+		log.Println("Ошибка записи в лог:", err)
+	}
+}
+
+func sendLogs(chatID int64) {
+	lang := chatLang(chatID)
+
+	entries, err := storage.Logs(chatID, 0, logsPageSize)
+	if err != nil {
+		log.Println("Ошибка чтения логов:", err)
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "logs_failed")))
+		return
+	}
+	if len(entries) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, i18n.T(lang, "logs_empty")))
+		return
+	}
+
+	var b strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&b, "%s | %s | %s: %s\n",
+			e.CreatedAt.Format("2006-01-02 15:04:05"),
+			i18n.T(lang, "log_action_"+e.Action),
+			i18n.T(lang, "log_name_label"),
+			e.Name)
+	}
+
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(i18n.T(lang, "logs_header"), b.String())))
+}
 
 func splitLines(s string) []string {
 	var lines []string
@@ -236,4 +1110,3 @@ func splitBy(s string, sep rune) []string {
 	}
 	return res
 }
-
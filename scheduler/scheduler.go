@@ -0,0 +1,152 @@
+// Package scheduler generalizes the one-shot timer goroutine into a
+// recurring-job subsystem: timers can fire once, daily, or on a cron
+// expression, and are kept in a priority queue ordered by next fire time.
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Job is a single scheduled fire, one-shot or repeating.
+type Job struct {
+	ID       string
+	ChatID   int64
+	Name     string
+	Schedule string // standard 5-field cron expression
+	NextFire time.Time
+	Repeat   bool
+}
+
+type jobHeap []*Job
+
+func (h jobHeap) Len() int            { return len(h) }
+func (h jobHeap) Less(i, j int) bool  { return h[i].NextFire.Before(h[j].NextFire) }
+func (h jobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(*Job)) }
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	job := old[n-1]
+	*h = old[:n-1]
+	return job
+}
+
+// Scheduler keeps upcoming fires in a priority queue and invokes onFire
+// for each job as it comes due, rescheduling repeating jobs afterwards.
+type Scheduler struct {
+	mu     sync.Mutex
+	jobs   jobHeap
+	onFire func(Job)
+	wake   chan struct{}
+}
+
+// New creates a Scheduler that calls onFire whenever a job comes due.
+func New(onFire func(Job)) *Scheduler {
+	return &Scheduler{onFire: onFire, wake: make(chan struct{}, 1)}
+}
+
+// Start launches the scheduler's background loop. It exits cleanly once
+// ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+// Add inserts or re-inserts a job into the queue.
+func (s *Scheduler) Add(job Job) {
+	s.mu.Lock()
+	heap.Push(&s.jobs, &job)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Remove drops a pending job by id. It reports whether a job was found.
+func (s *Scheduler) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, job := range s.jobs {
+		if job.ID == id {
+			heap.Remove(&s.jobs, i)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scheduler) run(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		if len(s.jobs) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		wait := time.Until(s.jobs[0].NextFire)
+		s.mu.Unlock()
+
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-s.wake:
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		s.mu.Lock()
+		if len(s.jobs) == 0 {
+			s.mu.Unlock()
+			continue
+		}
+		job := heap.Pop(&s.jobs).(*Job)
+		s.mu.Unlock()
+
+		// Recompute NextFire before invoking onFire, so the Job it
+		// receives already carries the real next fire time and can
+		// persist it (otherwise a restart would reload the stale,
+		// now-past NextFire and fire the job again immediately).
+		rescheduled := false
+		if job.Repeat {
+			if next, err := NextFire(job.Schedule, time.Now()); err == nil {
+				job.NextFire = next
+				rescheduled = true
+			}
+		}
+
+		s.onFire(*job)
+
+		if rescheduled {
+			s.Add(*job)
+		}
+	}
+}
+
+// NextFire parses a standard 5-field cron expression (also used to encode
+// "daily HH:MM" as "M H * * *") and returns its next fire time after from.
+func NextFire(schedule string, from time.Time) (time.Time, error) {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(from), nil
+}
@@ -0,0 +1,216 @@
+// Package storage persists timers and their activity log in SQLite so
+// both survive a bot restart.
+package storage
+
+import (
+	"time"
+
+	_ "modernc.org/sqlite"
+	"xorm.io/xorm"
+)
+
+// Status values for a Timer row.
+const (
+	StatusRunning = "running"
+	StatusStopped = "stopped"
+)
+
+// Timer is the persisted representation of a running or finished timer,
+// one-shot or recurring.
+type Timer struct {
+	ID          string `xorm:"pk"`
+	ChatID      int64  `xorm:"index"`
+	Name        string
+	StartTime   time.Time
+	Duration    time.Duration
+	Status      string `xorm:"index"`
+	AutoStopped bool
+
+	// Schedule is a standard 5-field cron expression, set only when
+	// Repeat is true.
+	Schedule string
+	NextFire time.Time
+	Repeat   bool
+}
+
+// LogEntry is a single recorded timer action, shown newest first by /logs.
+type LogEntry struct {
+	ID        int64 `xorm:"pk autoincr"`
+	ChatID    int64 `xorm:"index"`
+	Action    string
+	Name      string
+	CreatedAt time.Time `xorm:"index"`
+}
+
+// Preset is a named timer template a chat can reuse with /preset, e.g.
+// "pomodoro" for 25 minutes.
+type Preset struct {
+	ChatID  int64  `xorm:"pk"`
+	Name    string `xorm:"pk"`
+	Minutes int
+}
+
+// UserSettings is the persisted, per-chat configuration for the bot's
+// language and timer notifications.
+type UserSettings struct {
+	ChatID int64 `xorm:"pk"`
+
+	Language   string
+	TimeFormat string
+
+	// PreNotify is a comma-separated list of minute offsets (e.g.
+	// "5,60") at which startTimer should send an extra "time's almost
+	// up" ping before the timer's normal expiry.
+	PreNotify string
+
+	SoundEmoji bool
+}
+
+var engine *xorm.Engine
+
+// Open opens (and if needed creates) the SQLite database at path and
+// makes sure the schema is up to date.
+func Open(path string) error {
+	e, err := xorm.NewEngine("sqlite", path)
+	if err != nil {
+		return err
+	}
+	if err := e.Sync2(new(Timer), new(LogEntry), new(UserSettings), new(Preset)); err != nil {
+		return err
+	}
+	engine = e
+	return nil
+}
+
+// SaveTimer inserts a new timer row or updates an existing one with the
+// same ID.
+func SaveTimer(t *Timer) error {
+	has, err := engine.ID(t.ID).Exist(new(Timer))
+	if err != nil {
+		return err
+	}
+	if has {
+		_, err = engine.ID(t.ID).AllCols().Update(t)
+		return err
+	}
+	_, err = engine.Insert(t)
+	return err
+}
+
+// LoadActive returns every timer row still marked as running, e.g. those
+// left behind by an unclean shutdown.
+func LoadActive() ([]*Timer, error) {
+	var timers []*Timer
+	err := engine.Where("status = ?", StatusRunning).Find(&timers)
+	return timers, err
+}
+
+// MarkStopped flips a timer row to stopped and records whether it was
+// stopped automatically (timeout) or by the user.
+func MarkStopped(id string, autoStopped bool) error {
+	_, err := engine.ID(id).Cols("status", "auto_stopped").Update(&Timer{
+		Status:      StatusStopped,
+		AutoStopped: autoStopped,
+	})
+	return err
+}
+
+// UpdateNextFire persists a recurring timer's recomputed next fire time,
+// called after each fire so a restart reads the real next fire time
+// instead of replaying the one it was originally created with.
+func UpdateNextFire(id string, next time.Time) error {
+	_, err := engine.ID(id).Cols("next_fire").Update(&Timer{NextFire: next})
+	return err
+}
+
+// AppendLog records a single timer action for later display via /logs.
+func AppendLog(chatID int64, action, name string) error {
+	_, err := engine.Insert(&LogEntry{
+		ChatID:    chatID,
+		Action:    action,
+		Name:      name,
+		CreatedAt: time.Now(),
+	})
+	return err
+}
+
+// Logs returns up to limit log entries for chatID, newest first, skipping
+// the first offset rows.
+func Logs(chatID int64, offset, limit int) ([]*LogEntry, error) {
+	var entries []*LogEntry
+	err := engine.Where("chat_id = ?", chatID).
+		Desc("created_at").
+		Limit(limit, offset).
+		Find(&entries)
+	return entries, err
+}
+
+// LoadSettings returns chatID's settings row, or nil if it hasn't saved
+// any yet (the caller should fall back to defaults).
+func LoadSettings(chatID int64) (*UserSettings, error) {
+	settings := new(UserSettings)
+	has, err := engine.ID(chatID).Get(settings)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, nil
+	}
+	return settings, nil
+}
+
+// SavePreset inserts or updates a named preset for chatID.
+func SavePreset(chatID int64, name string, minutes int) error {
+	p := &Preset{ChatID: chatID, Name: name, Minutes: minutes}
+	has, err := engine.Where("chat_id = ? AND name = ?", chatID, name).Exist(new(Preset))
+	if err != nil {
+		return err
+	}
+	if has {
+		_, err = engine.Where("chat_id = ? AND name = ?", chatID, name).Cols("minutes").Update(p)
+		return err
+	}
+	_, err = engine.Insert(p)
+	return err
+}
+
+// ListPresets returns every preset saved for chatID.
+func ListPresets(chatID int64) ([]*Preset, error) {
+	var presets []*Preset
+	err := engine.Where("chat_id = ?", chatID).Asc("name").Find(&presets)
+	return presets, err
+}
+
+// GetPreset returns chatID's preset named name, or nil if there isn't one.
+func GetPreset(chatID int64, name string) (*Preset, error) {
+	preset := new(Preset)
+	has, err := engine.Where("chat_id = ? AND name = ?", chatID, name).Get(preset)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, nil
+	}
+	return preset, nil
+}
+
+// DeletePreset removes chatID's preset named name. It reports whether a
+// row was actually deleted.
+func DeletePreset(chatID int64, name string) (bool, error) {
+	n, err := engine.Where("chat_id = ? AND name = ?", chatID, name).Delete(new(Preset))
+	return n > 0, err
+}
+
+// SaveSettings inserts or updates chatID's settings row.
+func SaveSettings(s *UserSettings) error {
+	has, err := engine.ID(s.ChatID).Exist(new(UserSettings))
+	if err != nil {
+		return err
+	}
+	if has {
+		_, err = engine.ID(s.ChatID).AllCols().Update(s)
+		return err
+	}
+	_, err = engine.Insert(s)
+	return err
+}